@@ -0,0 +1,189 @@
+package caddy_i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ortfo/gettext/po"
+)
+
+// CatalogFormat loads and saves a translation catalog from disk in whatever file format
+// it's stored as, normalizing it to/from a po.File in memory so the rest of the module
+// (translate, savePO, extraction) only has to deal with one shape.
+type CatalogFormat interface {
+	// Extension is the file extension (without the dot) catalog files of this format use,
+	// e.g. "po" or "json".
+	Extension() string
+	// Load reads the catalog file at path.
+	Load(path string) (*po.File, error)
+	// Save writes poFile to path.
+	Save(poFile *po.File, path string) error
+}
+
+// catalogFormats maps the `format` Caddyfile directive's argument to its implementation.
+var catalogFormats = map[string]CatalogFormat{
+	"po":     poCatalogFormat{},
+	"gotext": gotextCatalogFormat{},
+}
+
+// resolveCatalogFormat looks up a CatalogFormat by the name used in the Caddyfile.
+func resolveCatalogFormat(name string) (CatalogFormat, error) {
+	format, ok := catalogFormats[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown catalog format %q, expected one of po, gotext", name)
+	}
+	return format, nil
+}
+
+// poCatalogFormat is the original, default catalog format: GNU gettext .po files.
+type poCatalogFormat struct{}
+
+func (poCatalogFormat) Extension() string { return "po" }
+
+func (poCatalogFormat) Load(path string) (*po.File, error) {
+	return po.LoadFile(path)
+}
+
+func (poCatalogFormat) Save(poFile *po.File, path string) error {
+	return poFile.Save(path)
+}
+
+// gotextCatalogFormat reads and writes the JSON layout produced by
+// golang.org/x/text/message/pipeline (messages.<lang>.json / out.gotext.json), so catalogs
+// generated by `gotext extract`/`gotext update` can be used as-is.
+type gotextCatalogFormat struct{}
+
+func (gotextCatalogFormat) Extension() string { return "json" }
+
+// gotextCatalog is the top-level shape of a pipeline JSON catalog.
+type gotextCatalog struct {
+	Language string          `json:"language"`
+	Messages []gotextMessage `json:"messages"`
+}
+
+// gotextMessage mirrors golang.org/x/text/message/pipeline.Message, keeping only the
+// fields this module round-trips. Context and PluralID/PluralTranslations are this
+// module's own additions: Context carries po.Message's MsgContext, and
+// PluralID/PluralTranslations are a simplified stand-in for pipeline's nested Select
+// translation type, carrying just po.Message's MsgIdPlural/MsgStrPlural.
+type gotextMessage struct {
+	ID                 string              `json:"id"`
+	Message            string              `json:"message"`
+	Translation        string              `json:"translation"`
+	Context            string              `json:"context,omitempty"`
+	Placeholders       []gotextPlaceholder `json:"placeholders,omitempty"`
+	Fuzzy              bool                `json:"fuzzy,omitempty"`
+	PluralID           string              `json:"pluralId,omitempty"`
+	PluralTranslations []string            `json:"pluralTranslations,omitempty"`
+}
+
+type gotextPlaceholder struct {
+	ID     string `json:"id"`
+	String string `json:"string"`
+}
+
+// placeholdersCommentPrefix tags the po.Message.ExtractedComment used to round-trip a
+// gotext message's Placeholders, since po.Message has nowhere else to carry them.
+const placeholdersCommentPrefix = "gotext-placeholders: "
+
+// encodePlaceholders serializes placeholders into a po.Message.ExtractedComment, or
+// returns "" when there are none to carry.
+func encodePlaceholders(placeholders []gotextPlaceholder) string {
+	if len(placeholders) == 0 {
+		return ""
+	}
+	encoded, err := json.Marshal(placeholders)
+	if err != nil {
+		return ""
+	}
+	return placeholdersCommentPrefix + string(encoded)
+}
+
+// decodePlaceholders recovers the placeholders encodePlaceholders stored in comment, or
+// returns nil if comment doesn't carry any.
+func decodePlaceholders(comment string) []gotextPlaceholder {
+	if !strings.HasPrefix(comment, placeholdersCommentPrefix) {
+		return nil
+	}
+	var placeholders []gotextPlaceholder
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(comment, placeholdersCommentPrefix)), &placeholders); err != nil {
+		return nil
+	}
+	return placeholders
+}
+
+// setFuzzy sets or clears the "fuzzy" comment flag on msg directly: po.Message's own
+// SetFuzzy is a no-op in the vendored gettext version, so it can't be relied on here.
+func setFuzzy(msg *po.Message, fuzzy bool) {
+	if fuzzy == msg.GetFuzzy() {
+		return
+	}
+	if fuzzy {
+		msg.Flags = append(msg.Flags, "fuzzy")
+		return
+	}
+	kept := msg.Flags[:0]
+	for _, flag := range msg.Flags {
+		if flag != "fuzzy" {
+			kept = append(kept, flag)
+		}
+	}
+	msg.Flags = kept
+}
+
+func (gotextCatalogFormat) Load(path string) (*po.File, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var catalog gotextCatalog
+	if err := json.Unmarshal(contents, &catalog); err != nil {
+		return nil, fmt.Errorf("while parsing gotext JSON catalog: %w", err)
+	}
+
+	poFile := &po.File{}
+	for _, msg := range catalog.Messages {
+		message := po.Message{
+			MsgId:        msg.ID,
+			MsgStr:       msg.Translation,
+			MsgContext:   msg.Context,
+			MsgIdPlural:  msg.PluralID,
+			MsgStrPlural: msg.PluralTranslations,
+		}
+		message.ExtractedComment = encodePlaceholders(msg.Placeholders)
+		setFuzzy(&message, msg.Fuzzy)
+		poFile.Messages = append(poFile.Messages, message)
+	}
+	return poFile, nil
+}
+
+func (gotextCatalogFormat) Save(poFile *po.File, path string) error {
+	languageCode := strings.TrimSuffix(filepath.Base(path), "."+gotextCatalogFormat{}.Extension())
+
+	catalog := gotextCatalog{
+		Language: languageCode,
+		Messages: make([]gotextMessage, 0, len(poFile.Messages)),
+	}
+	for _, msg := range poFile.Messages {
+		catalog.Messages = append(catalog.Messages, gotextMessage{
+			ID:                 msg.MsgId,
+			Message:            msg.MsgId,
+			Translation:        msg.MsgStr,
+			Context:            msg.MsgContext,
+			Placeholders:       decodePlaceholders(msg.ExtractedComment),
+			Fuzzy:              msg.GetFuzzy(),
+			PluralID:           msg.MsgIdPlural,
+			PluralTranslations: msg.MsgStrPlural,
+		})
+	}
+
+	contents, err := json.MarshalIndent(catalog, "", "    ")
+	if err != nil {
+		return fmt.Errorf("while encoding gotext JSON catalog: %w", err)
+	}
+	return os.WriteFile(path, contents, 0644)
+}
@@ -0,0 +1,82 @@
+package caddy_i18n
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ortfo/gettext/po"
+)
+
+func TestGotextCatalogFormatRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fr.json")
+
+	msg := po.Message{MsgId: "hello", MsgStr: "bonjour"}
+	msg.ExtractedComment = encodePlaceholders([]gotextPlaceholder{{ID: "Name", String: "%[1]s"}})
+	setFuzzy(&msg, true)
+
+	format := gotextCatalogFormat{}
+	if err := format.Save(&po.File{Messages: []po.Message{msg}}, path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if !strings.Contains(string(contents), `"language": "fr"`) {
+		t.Errorf("expected saved catalog to carry its language, got:\n%s", contents)
+	}
+
+	poFile, err := format.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(poFile.Messages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(poFile.Messages))
+	}
+
+	got := poFile.Messages[0]
+	if !got.GetFuzzy() {
+		t.Errorf("expected fuzzy flag to round-trip, got false")
+	}
+	if placeholders := decodePlaceholders(got.ExtractedComment); len(placeholders) != 1 || placeholders[0].ID != "Name" {
+		t.Errorf("expected placeholders to round-trip, got %+v", placeholders)
+	}
+}
+
+func TestGotextCatalogFormatRoundTripContextAndPlural(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fr.json")
+
+	msg := po.Message{
+		MsgId:        "item",
+		MsgContext:   "nav",
+		MsgIdPlural:  "items",
+		MsgStrPlural: []string{"objet", "objets"},
+	}
+
+	format := gotextCatalogFormat{}
+	if err := format.Save(&po.File{Messages: []po.Message{msg}}, path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	poFile, err := format.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(poFile.Messages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(poFile.Messages))
+	}
+
+	got := poFile.Messages[0]
+	if got.MsgContext != "nav" {
+		t.Errorf("expected context to round-trip, got %q", got.MsgContext)
+	}
+	if got.MsgIdPlural != "items" {
+		t.Errorf("expected plural msgid to round-trip, got %q", got.MsgIdPlural)
+	}
+	if len(got.MsgStrPlural) != 2 || got.MsgStrPlural[0] != "objet" || got.MsgStrPlural[1] != "objets" {
+		t.Errorf("expected plural translations to round-trip, got %+v", got.MsgStrPlural)
+	}
+}
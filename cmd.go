@@ -0,0 +1,66 @@
+package caddy_i18n
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/caddyserver/caddy/v2"
+	caddycmd "github.com/caddyserver/caddy/v2/cmd"
+)
+
+func init() {
+	caddycmd.RegisterCommand(caddycmd.Command{
+		Name:  "i18n-extract",
+		Usage: "[--translations <dir>] [--languages <lang,...>] [--source-language <lang>] [--html-attribute <attr>] [--html-tag <tag>] [--format po|gotext] <path...>",
+		Short: "Harvest translatable strings from HTML files into catalogs",
+		Long: `
+Scans the given paths (directories are walked recursively) for .html files, finds every
+i18n marker the same way the i18n handler does while serving a page, and merges newly
+found strings into each target language's catalog: new messages are added untranslated,
+existing translations are preserved, and messages no longer found are marked obsolete.
+
+This lets CI pipelines keep .po/gotext JSON catalogs in sync with the templates
+without running a live server with update_translations enabled.`,
+		Flags: extractFlags(),
+		Func:  cmdExtract,
+	})
+}
+
+func extractFlags() *flag.FlagSet {
+	fs := flag.NewFlagSet("i18n-extract", flag.ExitOnError)
+	fs.String("translations", "i18n", "Directory where catalog files are stored")
+	fs.String("languages", "", "Comma-separated list of target language codes")
+	fs.String("source-language", "en", "Language code of the content the HTML is written in")
+	fs.String("html-attribute", "i18n", "HTML attribute used to mark translatable content")
+	fs.String("html-tag", "i18n", "HTML tag used to mark translatable content")
+	fs.String("format", "po", "Catalog file format: po or gotext")
+	return fs
+}
+
+func cmdExtract(fl caddycmd.Flags) (int, error) {
+	paths := fl.Args()
+	if len(paths) == 0 {
+		return caddy.ExitCodeFailedStartup, fmt.Errorf("usage: caddy i18n-extract [flags] <path...>")
+	}
+
+	languagesArg := fl.String("languages")
+	if languagesArg == "" {
+		return caddy.ExitCodeFailedStartup, fmt.Errorf("--languages is required")
+	}
+
+	cfg := I18n{
+		Translations:   fl.String("translations"),
+		HTMLAttribute:  fl.String("html-attribute"),
+		HTMLTag:        fl.String("html-tag"),
+		SourceLanguage: fl.String("source-language"),
+		Languages:      strings.Split(languagesArg, ","),
+		Format:         fl.String("format"),
+	}
+
+	if err := Extract(paths, cfg); err != nil {
+		return caddy.ExitCodeFailedStartup, err
+	}
+
+	return caddy.ExitCodeSuccess, nil
+}
@@ -0,0 +1,222 @@
+package caddy_i18n
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/ortfo/gettext/po"
+	"golang.org/x/net/html"
+	"golang.org/x/text/language"
+)
+
+// markerSelector is the CSS selector used to find translation markers: either the
+// marker tag itself, or any element carrying the marker attribute. It's used by
+// scanMarkersForExtraction (offline); the live translator in stream.go recognizes the
+// same markers token by token in startMarkerCapture.
+func markerSelector(markerTag, markerAttribute string) string {
+	return fmt.Sprintf("%s, [%s]", markerTag, markerAttribute)
+}
+
+// extractedMessage is a translatable string discovered while scanning HTML, in the
+// shape a po.Message stores it.
+type extractedMessage struct {
+	MsgId       string
+	MsgIdPlural string
+	MsgContext  string
+}
+
+// scanMarkersForExtraction walks doc the same way the live translator does, returning
+// every msgid it finds (including msgid_plural/context on plural markers, and attribute
+// values marked up with i18n-attrs), without attempting to translate anything. It reads
+// markers through parseMarkerAttrs/classifyAttrsTarget, the same attribute-parsing
+// functions stream.go's startMarkerCapture/rewriteStartTag use at request time, so
+// extraction can't drift from what's actually translated.
+func scanMarkersForExtraction(doc *goquery.Document, markerTag, markerAttribute string) []extractedMessage {
+	var messages []extractedMessage
+	names := newMarkerAttrNames(markerAttribute)
+
+	doc.Find(markerSelector(markerTag, markerAttribute)).Each(func(_ int, element *goquery.Selection) {
+		if len(element.Nodes) == 0 {
+			return
+		}
+		parsed := parseMarkerAttrs(element.Nodes[0].Attr, names)
+		innerHTML, _ := element.Html()
+		innerHTML = html.UnescapeString(innerHTML)
+		innerHTML = strings.TrimSpace(innerHTML)
+		if innerHTML == "" {
+			return
+		}
+		messages = append(messages, extractedMessage{MsgId: innerHTML, MsgIdPlural: parsed.idPlural, MsgContext: parsed.context})
+	})
+
+	doc.Find(fmt.Sprintf("[%s]", attrsFlagName(markerAttribute))).Each(func(_ int, element *goquery.Selection) {
+		if len(element.Nodes) == 0 {
+			return
+		}
+		for _, attribute := range element.Nodes[0].Attr {
+			targetKey, isCommaList, matched := classifyAttrsTarget(attribute.Key, markerAttribute)
+			if !matched || targetKey == "" {
+				continue
+			}
+			if isCommaList {
+				for _, val := range strings.Split(attribute.Val, ",") {
+					messages = append(messages, extractedMessage{MsgId: val})
+				}
+				continue
+			}
+			messages = append(messages, extractedMessage{MsgId: attribute.Val})
+		}
+	})
+
+	return messages
+}
+
+// Extract walks paths (directories are scanned recursively for .html files; individual
+// .html files are scanned directly, other files are skipped) and merges every
+// translatable string it finds into each of cfg.Languages' catalogs: new messages are
+// added with an empty translation, existing translations are preserved, and messages no
+// longer found are marked obsolete. scanMarkersForExtraction reads markers through the
+// same attribute-parsing functions as the live translator (translateTokens), so
+// extraction can't drift from what's actually translated at request time, giving CI
+// pipelines a deterministic way to sync translators' files without hitting the server.
+func Extract(paths []string, cfg I18n) error {
+	sourceLanguage, err := language.Parse(cfg.SourceLanguage)
+	if err != nil {
+		return fmt.Errorf("invalid source language code: %w", err)
+	}
+
+	format, err := resolveCatalogFormat(cfg.Format)
+	if err != nil {
+		return err
+	}
+
+	var found []extractedMessage
+	for _, path := range paths {
+		err := filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() || !strings.HasSuffix(file, ".html") {
+				return nil
+			}
+
+			contents, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("while reading %s: %w", file, err)
+			}
+			parsed, err := html.Parse(strings.NewReader(string(contents)))
+			if err != nil {
+				return fmt.Errorf("while parsing %s: %w", file, err)
+			}
+
+			found = append(found, scanMarkersForExtraction(goquery.NewDocumentFromNode(parsed), cfg.HTMLTag, cfg.HTMLAttribute)...)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("while walking %s: %w", path, err)
+		}
+	}
+
+	for _, languageCodeStr := range cfg.Languages {
+		languageCode, err := language.Parse(languageCodeStr)
+		if err != nil {
+			return fmt.Errorf("invalid language code %q: %w", languageCodeStr, err)
+		}
+
+		catalogPath := filepath.Join(cfg.Translations, fmt.Sprintf("%s.%s", languageCode, format.Extension()))
+		poFile, err := format.Load(catalogPath)
+		if err != nil {
+			poFile = &po.File{}
+		}
+		poFile.SetSourceLanguage(sourceLanguage)
+
+		poFile.Messages = mergeExtractedMessages(poFile.Messages, found)
+
+		if err := format.Save(poFile, catalogPath); err != nil {
+			return fmt.Errorf("while saving extracted catalog for %s: %w", languageCode, err)
+		}
+	}
+
+	return nil
+}
+
+// obsoleteFlag marks a po.Message no longer found during extraction, via the comment
+// flags mechanism po.Message already uses for "fuzzy" (it has no dedicated Obsolete
+// field of its own).
+const obsoleteFlag = "obsolete"
+
+// isObsolete reports whether msg carries the obsoleteFlag.
+func isObsolete(msg po.Message) bool {
+	for _, flag := range msg.Flags {
+		if flag == obsoleteFlag {
+			return true
+		}
+	}
+	return false
+}
+
+// setObsolete adds or removes the obsoleteFlag on msg.
+func setObsolete(msg *po.Message, obsolete bool) {
+	if obsolete == isObsolete(*msg) {
+		return
+	}
+	if obsolete {
+		msg.Flags = append(msg.Flags, obsoleteFlag)
+		return
+	}
+	kept := msg.Flags[:0]
+	for _, flag := range msg.Flags {
+		if flag != obsoleteFlag {
+			kept = append(kept, flag)
+		}
+	}
+	msg.Flags = kept
+}
+
+// mergeExtractedMessages folds newly found messages into an existing catalog:
+// messages still found keep their existing translation (and are no longer obsolete),
+// new messages are added untranslated, and existing messages no longer found are kept
+// but marked obsolete rather than deleted outright.
+func mergeExtractedMessages(existing []po.Message, found []extractedMessage) []po.Message {
+	existingByKey := make(map[string]po.Message, len(existing))
+	for _, msg := range existing {
+		existingByKey[msg.MsgId+msg.MsgContext] = msg
+	}
+
+	seen := make(map[string]bool, len(found))
+	merged := make([]po.Message, 0, len(existing)+len(found))
+
+	for _, msg := range found {
+		key := msg.MsgId + msg.MsgContext
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if existingMsg, ok := existingByKey[key]; ok {
+			existingMsg.MsgIdPlural = msg.MsgIdPlural
+			setObsolete(&existingMsg, false)
+			merged = append(merged, existingMsg)
+		} else {
+			merged = append(merged, po.Message{
+				MsgId:       msg.MsgId,
+				MsgIdPlural: msg.MsgIdPlural,
+				MsgContext:  msg.MsgContext,
+			})
+		}
+	}
+
+	for key, msg := range existingByKey {
+		if seen[key] {
+			continue
+		}
+		setObsolete(&msg, true)
+		merged = append(merged, msg)
+	}
+
+	return merged
+}
@@ -0,0 +1,31 @@
+package caddy_i18n
+
+import (
+	"testing"
+
+	"github.com/ortfo/gettext/po"
+)
+
+func TestMergeExtractedMessagesMarksObsolete(t *testing.T) {
+	existing := []po.Message{
+		{MsgId: "still here", MsgStr: "toujours là"},
+		{MsgId: "gone now", MsgStr: "parti"},
+	}
+
+	merged := mergeExtractedMessages(existing, []extractedMessage{{MsgId: "still here"}})
+
+	byID := make(map[string]po.Message, len(merged))
+	for _, msg := range merged {
+		byID[msg.MsgId] = msg
+	}
+
+	if isObsolete(byID["still here"]) {
+		t.Errorf("expected %q to not be obsolete", "still here")
+	}
+	if !isObsolete(byID["gone now"]) {
+		t.Errorf("expected %q to be marked obsolete", "gone now")
+	}
+	if byID["gone now"].MsgStr != "parti" {
+		t.Errorf("expected obsolete message to keep its translation, got %q", byID["gone now"].MsgStr)
+	}
+}
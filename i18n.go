@@ -1,18 +1,14 @@
 package caddy_i18n
 
 import (
-	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 	"time"
 
-	"github.com/PuerkitoBio/goquery"
 	mapset "github.com/deckarep/golang-set"
 	"github.com/ortfo/gettext/po"
 	"go.uber.org/zap"
-	"golang.org/x/net/html"
 	"golang.org/x/text/language"
 )
 
@@ -28,11 +24,12 @@ type translationCatalog struct {
 	markerAttribute  string
 	markerTag        string
 	exposeToJS       bool
+	format           CatalogFormat
 	*zap.Logger
 }
 
 func (t translationCatalog) poFilePath() string {
-	return filepath.Join(t.poFilesDirectory, fmt.Sprintf("%s.po", t.language))
+	return filepath.Join(t.poFilesDirectory, fmt.Sprintf("%s.%s", t.language, t.format.Extension()))
 }
 
 func (t translationCatalog) unusedMessagesFilePath() string {
@@ -41,112 +38,21 @@ func (t translationCatalog) unusedMessagesFilePath() string {
 
 type translationsCatalogs map[language.Tag]translationCatalog
 
-func (t *translationCatalog) translatePage(source []byte) (string, error) {
-	parsed, err := html.Parse(bytes.NewReader(source))
-	if err != nil {
-		return "", fmt.Errorf("while parsing output page HTML: %w", err)
-	}
-
-	return t.translate(parsed), nil
+// catalogState bundles the loaded catalogs with the language matcher built from them, so
+// they can be swapped out atomically (see watchTranslations) and readers never observe a
+// matcher that doesn't correspond to the catalogs it was built from.
+type catalogState struct {
+	catalogs translationsCatalogs
+	matcher  language.Matcher
 }
 
-// translate translates the given html node to the target language, removing the translation markers
-func (t *translationCatalog) translate(root *html.Node) string {
-	// Open files
-	doc := goquery.NewDocumentFromNode(root)
-
-	// Expose JS constant
-	if t.exposeToJS {
-		doc.Find("head").AppendHtml(fmt.Sprintf("<script>window.i18nLanguage = %q; window.i18nSourceLanguage = %q;</script>", t.language, t.sourceLanguage))
-	}
-
-	doc.Find(fmt.Sprintf("%s, [%s]", t.markerTag, t.markerAttribute)).Each(func(_ int, element *goquery.Selection) {
-		element.RemoveAttr(t.markerAttribute)
-		msgContext, _ := element.Attr(fmt.Sprintf("%s-context", t.markerAttribute))
-		element.RemoveAttr(fmt.Sprintf("%s-context", t.markerAttribute))
-		if t.language != t.sourceLanguage {
-			innerHTML, _ := element.Html()
-			innerHTML = html.UnescapeString(innerHTML)
-			innerHTML = strings.TrimSpace(innerHTML)
-			if innerHTML == "" {
-				return
-			}
-			translated, err := t.getTranslation(innerHTML, msgContext)
-			if err != nil {
-				// color.Yellow("[%s] Missing translation for %q", t.language, innerHTML)
-
-				t.missingMessages = append(t.missingMessages, po.Message{
-					MsgId:      innerHTML,
-					MsgContext: msgContext,
-				})
-			} else {
-				element.SetHtml(translated)
-			}
-		}
-	})
-	doc.Find(fmt.Sprintf("[%s-keep-on]", t.markerAttribute)).Each(func(_ int, element *goquery.Selection) {
-		// delete node if the current language is not the value of the attribute
-		// useful for conditionally including already-translated content (e.g. user-generated content)
-		if element.AttrOr(fmt.Sprintf("%s-keep-on", t.markerAttribute), "") != t.language.String() {
-			element.Remove()
-		}
-		element.RemoveAttr(fmt.Sprintf("%s-keep-on", t.markerAttribute))
-	})
-	doc.Find(fmt.Sprintf("[%s-attrs]", t.markerAttribute)).Each(func(_ int, element *goquery.Selection) {
-		element.RemoveAttr(fmt.Sprintf("%s-attrs", t.markerAttribute))
-		// find all attributes that start with "i18n:"
-		for _, attribute := range element.Nodes[0].Attr {
-			if !strings.HasPrefix(attribute.Key, fmt.Sprintf("%s:", t.markerAttribute)) {
-				continue
-			}
-			if strings.HasPrefix(attribute.Key, fmt.Sprintf("%s:commas:", t.markerAttribute)) {
-				// Multi-valued attributes
-				translated := attribute.Val
-				if t.language != t.sourceLanguage {
-					translated = ""
-					for _, val := range strings.Split(attribute.Val, ",") {
-						translatedItem, err := t.getTranslation(val, "")
-						if err != nil {
-							t.Warn("missing translation", zap.String("msgid", val))
-							t.missingMessages = append(t.missingMessages, po.Message{
-								MsgId:      val,
-								MsgContext: "",
-							})
-							translatedItem = val
-						}
-						translated += "," + translatedItem
-					}
-					translated = strings.Trim(translated, ",")
-				}
-				element.RemoveAttr(attribute.Key)
-				element.SetAttr(strings.TrimPrefix(attribute.Key, fmt.Sprintf("%s:commas:", t.markerAttribute)), translated)
-			} else {
-				// Translate the attribute
-				translated := attribute.Val
-				if t.language != t.sourceLanguage {
-					var err error
-					translated, err = t.getTranslation(attribute.Val, "")
-					if err != nil {
-						t.Warn("missing translation", zap.String("msgid", attribute.Val))
-						t.missingMessages = append(t.missingMessages, po.Message{
-							MsgId:      attribute.Val,
-							MsgContext: "",
-						})
-						translated = attribute.Val
-					}
-				}
-				element.RemoveAttr(attribute.Key)
-				element.SetAttr(strings.TrimPrefix(attribute.Key, fmt.Sprintf("%s:", t.markerAttribute)), translated)
-			}
-		}
-	})
-	htmlString, _ := doc.Html()
-	htmlString = strings.ReplaceAll(htmlString, fmt.Sprintf("<%s>", t.markerTag), "")
-	htmlString = strings.ReplaceAll(htmlString, fmt.Sprintf("</%s>", t.markerTag), "")
-	return htmlString
+// newCatalogState builds a catalogState from a set of catalogs, deriving the matcher.
+func newCatalogState(catalogs translationsCatalogs) *catalogState {
+	return &catalogState{catalogs: catalogs, matcher: language.NewMatcher(keys(catalogs))}
 }
 
-// loadTranslations reads from i18n/[language].po to load translations
+// loadTranslations reads from i18n/[language].{po,json} (depending on the configured
+// format) to load translations
 func (m *I18n) loadTranslations() (translationsCatalogs, error) {
 	translations := make(translationsCatalogs)
 	sourceLanguage, err := language.Parse(m.SourceLanguage)
@@ -154,31 +60,25 @@ func (m *I18n) loadTranslations() (translationsCatalogs, error) {
 		return translations, fmt.Errorf("invalid source language code: %w", err)
 	}
 
+	format, err := resolveCatalogFormat(m.Format)
+	if err != nil {
+		return translations, err
+	}
+
 	for _, languageCodeStr := range m.Languages {
 		languageCode, err := language.Parse(languageCodeStr)
 		if err != nil {
 			return translations, fmt.Errorf("invalid language code %q: %w", languageCodeStr, err)
 		}
 
-		translationsFilepath := fmt.Sprintf("%s/%s.po", m.Translations, languageCode)
-		poFile, err := po.LoadFile(translationsFilepath)
+		translationsFilepath := fmt.Sprintf("%s/%s.%s", m.Translations, languageCode, format.Extension())
+		poFile, err := format.Load(translationsFilepath)
 		if err != nil {
 			return nil, fmt.Errorf("while loading translations for %s: %w", languageCode, err)
 		}
 
 		poFile.SetSourceLanguage(sourceLanguage)
-		translations[languageCode] = translationCatalog{
-			poFile:           poFile,
-			seenMessages:     mapset.NewSet(),
-			missingMessages:  make([]po.Message, 0),
-			language:         languageCode,
-			sourceLanguage:   sourceLanguage,
-			poFilesDirectory: m.Translations,
-			markerAttribute:  m.HTMLAttribute,
-			markerTag:        m.HTMLTag,
-			exposeToJS:       m.ExposeToJS,
-			Logger:           m.Logger.With(zap.String("lang", languageCode.String())),
-		}
+		translations[languageCode] = m.newCatalog(languageCode, sourceLanguage, poFile, format)
 		filledTranslationsCount := 0
 		for _, msg := range poFile.Messages {
 			if msg.MsgId != "" {
@@ -190,6 +90,25 @@ func (m *I18n) loadTranslations() (translationsCatalogs, error) {
 	return translations, nil
 }
 
+// newCatalog builds the translationCatalog for a single language from an already-loaded
+// poFile, so loadTranslations (at Provision) and reloadCatalog (on watch) build catalogs
+// the exact same way.
+func (m *I18n) newCatalog(languageCode, sourceLanguage language.Tag, poFile *po.File, format CatalogFormat) translationCatalog {
+	return translationCatalog{
+		poFile:           poFile,
+		seenMessages:     mapset.NewSet(),
+		missingMessages:  make([]po.Message, 0),
+		language:         languageCode,
+		sourceLanguage:   sourceLanguage,
+		poFilesDirectory: m.Translations,
+		markerAttribute:  m.HTMLAttribute,
+		markerTag:        m.HTMLTag,
+		exposeToJS:       m.ExposeToJS,
+		format:           format,
+		Logger:           m.Logger.With(zap.String("lang", languageCode.String())),
+	}
+}
+
 func (t translationCatalog) unusedMessages() []po.Message {
 	unused := make([]po.Message, 0)
 	for _, message := range t.poFile.Messages {
@@ -238,8 +157,8 @@ func (t translationCatalog) writeUnusedMessages() (count int, err error) {
 // 	}
 // }
 
-// savePO writes the .po file to the disk, with its potential modifications
-// It removes duplicate messages beforehand
+// savePO writes the catalog file to disk in the configured format, with its potential
+// modifications. It removes duplicate messages beforehand
 func (t *translationCatalog) savePO() {
 	// TODO: sort file after saving, (po.File).Save is not stable... (creates unecessary diffs in git)
 	// Remove unused messages with empty msgstrs
@@ -269,7 +188,9 @@ func (t *translationCatalog) savePO() {
 	}
 	// Sort them to guarantee a stable write
 	t.poFile.Messages = dedupedMessages
-	t.poFile.Save(t.poFilePath())
+	if err := t.format.Save(t.poFile, t.poFilePath()); err != nil {
+		t.Warn("while saving catalog", zap.String("path", t.poFilePath()), zap.Error(err))
+	}
 }
 
 // getTranslation returns the msgstr corresponding to msgid and msgctxt from the .po file
@@ -286,3 +207,23 @@ func (t translationCatalog) getTranslation(msgid string, msgctxt string) (string
 	}
 	return "", fmt.Errorf("cannot find msgstr in %s with msgid=%q and msgctx=%q", t.language, msgid, msgctxt)
 }
+
+// getTranslationN returns the plural-aware msgstr corresponding to msgid/msgidPlural and
+// msgctxt, selecting the msgstr[] entry for the CLDR plural category n falls into in the
+// target language. If not found, it returns an error.
+func (t translationCatalog) getTranslationN(msgid string, msgidPlural string, msgctxt string, n float64) (string, error) {
+	if msgid == "" {
+		return "", nil
+	}
+	t.seenMessages.Add(msgid + msgctxt)
+	index := pluralFormIndex(t.language, n)
+	for _, message := range t.poFile.Messages {
+		if message.MsgId == msgid && message.MsgIdPlural == msgidPlural && message.MsgContext == msgctxt {
+			if index < len(message.MsgStrPlural) && message.MsgStrPlural[index] != "" {
+				return message.MsgStrPlural[index], nil
+			}
+			break
+		}
+	}
+	return "", fmt.Errorf("cannot find msgstr[%d] in %s with msgid=%q msgid_plural=%q and msgctx=%q", index, t.language, msgid, msgidPlural, msgctxt)
+}
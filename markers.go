@@ -0,0 +1,80 @@
+package caddy_i18n
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// markerAttrNames are the attribute keys that make up a translation marker, derived once
+// from the configured marker attribute so the live translator (stream.go) and Extract
+// can't drift on what they're called.
+type markerAttrNames struct {
+	context string
+	plural  string
+	count   string
+}
+
+func newMarkerAttrNames(markerAttribute string) markerAttrNames {
+	return markerAttrNames{
+		context: markerAttribute + "-context",
+		plural:  markerAttribute + "-plural",
+		count:   markerAttribute + "-count",
+	}
+}
+
+// parsedMarkerAttrs is the msgctxt/msgid_plural/count a marker element carries, read off
+// its attributes by parseMarkerAttrs.
+type parsedMarkerAttrs struct {
+	context  string
+	idPlural string
+	count    string
+	hasCount bool
+}
+
+// parseMarkerAttrs reads a marker element's context/plural/count attributes from attrs
+// (an attribute list from either a tokenizer html.Token or a parsed goquery node — both
+// share golang.org/x/net/html's html.Attribute), so the live translator and Extract read
+// markers exactly the same way.
+func parseMarkerAttrs(attrs []html.Attribute, names markerAttrNames) parsedMarkerAttrs {
+	var parsed parsedMarkerAttrs
+	for _, attribute := range attrs {
+		switch attribute.Key {
+		case names.context:
+			parsed.context = attribute.Val
+		case names.plural:
+			parsed.idPlural = attribute.Val
+		case names.count:
+			parsed.count, parsed.hasCount = attribute.Val, true
+		}
+	}
+	return parsed
+}
+
+// attrsFlagName is the i18n-attrs attribute that opts an element's other attributes into
+// translation.
+func attrsFlagName(markerAttribute string) string {
+	return markerAttribute + "-attrs"
+}
+
+// classifyAttrsTarget reports how key, found on an element carrying the i18n-attrs flag,
+// participates in that convention: matched=false means key is unrelated and should be
+// left alone; matched=true with targetKey=="" means key is the i18n-attrs flag itself
+// (to be dropped); matched=true with targetKey!="" means key (i18n:name or
+// i18n:commas:name) should be translated and renamed to targetKey. Shared between
+// rewriteStartTag (live translation) and Extract so they can't disagree on what
+// i18n-attrs marks.
+func classifyAttrsTarget(key, markerAttribute string) (targetKey string, isCommaList, matched bool) {
+	commasPrefix := markerAttribute + ":commas:"
+	prefix := markerAttribute + ":"
+	switch {
+	case key == attrsFlagName(markerAttribute):
+		return "", false, true
+	case strings.HasPrefix(key, commasPrefix):
+		return strings.TrimPrefix(key, commasPrefix), true, true
+	case strings.HasPrefix(key, prefix):
+		return strings.TrimPrefix(key, prefix), false, true
+	default:
+		return "", false, false
+	}
+}
@@ -2,12 +2,11 @@
 package caddy_i18n
 
 import (
-	"bytes"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"strings"
+	"sync/atomic"
 
 	"github.com/caddyserver/caddy/v2"
 	"github.com/caddyserver/caddy/v2/caddyconfig/httpcaddyfile"
@@ -35,10 +34,17 @@ type I18n struct {
 	UpdateTranslations bool `json:"update_translations,omitempty"`
 	// Include a <script>window.i18nLanguage = "...";</script> in the response to expose the language code to JavaScript.
 	ExposeToJS bool `json:"expose_to_js,omitempty"`
-
-	catalogs        translationsCatalogs
+	// The catalog file format: po (GNU gettext, the default) or gotext (golang.org/x/text/message/pipeline JSON).
+	Format string `json:"format,omitempty"`
+	// Ordered list of places to look up the requester's language: path, cookie=<name>, query=<name>, header. Defaults to header (Accept-Language).
+	LanguageSources []string `json:"language_sources,omitempty"`
+	// When the path source matches, strip its language segment from the URL path before the next handler runs.
+	StripPathPrefix bool `json:"path_prefix_strip,omitempty"`
+	// Watch the translations directory for changes and hot-reload catalogs without a config reload. Disabled by default.
+	Watch bool `json:"watch,omitempty"`
+
+	state           *atomic.Pointer[catalogState]
 	tagToCatalogKey map[language.Tag]string
-	languageMatcher language.Matcher
 	*zap.Logger
 }
 
@@ -49,6 +55,7 @@ var defaultConfig = I18n{
 	SourceLanguage:     "en",
 	tagToCatalogKey:    make(map[language.Tag]string),
 	UpdateTranslations: false,
+	Format:             "po",
 }
 
 func init() {
@@ -78,12 +85,31 @@ func (m *I18n) Provision(ctx caddy.Context) error {
 		return fmt.Errorf("not all declared languages have translations")
 	}
 
-	m.languageMatcher = language.NewMatcher(keys(catalogs))
-	m.catalogs = catalogs
+	m.state = new(atomic.Pointer[catalogState])
+	m.state.Store(newCatalogState(catalogs))
+
+	if m.Watch {
+		if err := m.watchTranslations(ctx); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 func (m *I18n) Validate() error {
+	if _, err := resolveCatalogFormat(m.Format); err != nil {
+		return err
+	}
+
+	for _, raw := range m.LanguageSources {
+		switch parseLanguageSource(raw).kind {
+		case "path", "cookie", "query", "header":
+		default:
+			return fmt.Errorf("invalid language source %q, expected one of path, cookie=<name>, query=<name>, header", raw)
+		}
+	}
+
 	if len(m.Languages) == 0 {
 		return fmt.Errorf("no languages provided. Use languages directive to specify languages you support (languages that have an LANGUAGE.po file in the translations directory, which can be be configured with the translations directive) list (spaces separated)")
 	}
@@ -99,48 +125,46 @@ func (m *I18n) Validate() error {
 		}
 	}
 
+	catalogs := m.state.Load().catalogs
 	for _, lang := range m.Languages {
-		if _, ok := (m.catalogs)[language.MustParse(lang)]; !ok {
-			return fmt.Errorf("no translations found for language %s. available languages: %v", language.MustParse(lang), keys(m.catalogs))
+		if _, ok := catalogs[language.MustParse(lang)]; !ok {
+			return fmt.Errorf("no translations found for language %s. available languages: %v", language.MustParse(lang), keys(catalogs))
 		}
 	}
 
 	return nil
 }
 
-func shouldBuffer(status int, header http.Header) bool {
-	return status >= 200 && status < 400 && strings.HasPrefix(header.Get("Content-Type"), "text/html")
-}
-
 func (m *I18n) ServeHTTP(w http.ResponseWriter, r *http.Request, next caddyhttp.Handler) error {
-	untranslated := new(bytes.Buffer)
-	recorder := caddyhttp.NewResponseRecorder(w, untranslated, shouldBuffer)
-	err := next.ServeHTTP(recorder, r)
-	if err != nil {
-		return err
-	}
-	if !recorder.Buffered() {
-		return nil
+	acceptedLanguages, pathWithoutPrefix, matchedPath := m.negotiate(r)
+	if matchedPath && m.StripPathPrefix {
+		r.URL.Path = pathWithoutPrefix
 	}
 
-	acceptedLanguages := r.Header.Get("Accept-Language")
-	lang, _ := language.MatchStrings(m.languageMatcher, acceptedLanguages)
+	state := m.state.Load()
+	lang, _ := language.MatchStrings(state.matcher, acceptedLanguages)
 
-	translations, ok := (m.catalogs)[lang]
+	translations, ok := state.catalogs[lang]
 	if !ok {
-		return fmt.Errorf("no translations found for language %s. available translations: %v", lang, keys(m.catalogs))
+		return fmt.Errorf("no translations found for language %s. available translations: %v", lang, keys(state.catalogs))
 	}
 	w.Header().Set("Language", translations.language.String())
+	w.Header().Set("Content-Language", translations.language.String())
+	if vary := varyOn(m.LanguageSources); len(vary) > 0 {
+		w.Header().Set("Vary", strings.Join(vary, ", "))
+	}
 
-	translated, err := translations.translatePage(untranslated.Bytes())
-	if err != nil {
-		return fmt.Errorf("could not translate %s to %s: %w", r.RequestURI, lang, err)
+	if repl, ok := r.Context().Value(caddy.ReplacerCtxKey).(*caddy.Replacer); ok {
+		repl.Set("http.i18n.language", translations.language.String())
 	}
 
-	w.WriteHeader(recorder.Status())
-	_, err = io.WriteString(w, translated)
+	tw := newTranslatingResponseWriter(w, &translations)
+	err := next.ServeHTTP(tw, r)
+	if closeErr := tw.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
-		return fmt.Errorf("while writing translated response to %s in %s: %w", r.RequestURI, lang, err)
+		return fmt.Errorf("while translating %s to %s: %w", r.RequestURI, lang, err)
 	}
 
 	if m.UpdateTranslations {
@@ -198,6 +222,24 @@ func parseCaddyfileHandler(h httpcaddyfile.Helper) (caddyhttp.MiddlewareHandler,
 
 		case "expose_to_js":
 			module.ExposeToJS = true
+
+		case "format":
+			if !d.NextArg() {
+				return module, fmt.Errorf("format is missing a value")
+			}
+			module.Format = d.Val()
+
+		case "language_sources":
+			module.LanguageSources = d.RemainingArgs()
+			if len(module.LanguageSources) == 0 {
+				return module, fmt.Errorf("language_sources is missing a value")
+			}
+
+		case "path_prefix_strip":
+			module.StripPathPrefix = true
+
+		case "watch":
+			module.Watch = true
 		}
 	}
 
@@ -0,0 +1,117 @@
+package caddy_i18n
+
+import (
+	"net/http"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+const defaultLanguageSource = "header"
+
+// languageSource describes one place to look for the requester's preferred language,
+// as configured by the language_sources directive (e.g. "path", "cookie=lang", "query=hl").
+type languageSource struct {
+	kind string // "path", "cookie", "query", or "header"
+	name string // the cookie/query parameter name, for the "cookie"/"query" kinds
+}
+
+// parseLanguageSource parses one language_sources argument, e.g. "cookie=lang" into
+// {kind: "cookie", name: "lang"}, or "path" into {kind: "path"}.
+func parseLanguageSource(raw string) languageSource {
+	kind, name, hasName := strings.Cut(raw, "=")
+	if !hasName {
+		return languageSource{kind: kind}
+	}
+	return languageSource{kind: kind, name: name}
+}
+
+// negotiate walks m.LanguageSources in order and returns the first language string it
+// finds. When the match comes from the "path" source, it also returns the URL path with
+// its leading language segment stripped and matchedPath=true, so ServeHTTP can rewrite
+// r.URL.Path when path_prefix_strip is enabled.
+func (m *I18n) negotiate(r *http.Request) (acceptedLanguages string, pathWithoutPrefix string, matchedPath bool) {
+	sources := m.LanguageSources
+	if len(sources) == 0 {
+		sources = []string{defaultLanguageSource}
+	}
+
+	for _, raw := range sources {
+		source := parseLanguageSource(raw)
+		switch source.kind {
+		case "path":
+			segment, rest, ok := splitLanguagePrefix(r.URL.Path, m.state.Load().catalogs)
+			if !ok {
+				continue
+			}
+			return segment, rest, true
+
+		case "cookie":
+			cookie, err := r.Cookie(source.name)
+			if err != nil || cookie.Value == "" {
+				continue
+			}
+			return cookie.Value, "", false
+
+		case "query":
+			value := r.URL.Query().Get(source.name)
+			if value == "" {
+				continue
+			}
+			return value, "", false
+
+		case "header":
+			if value := r.Header.Get("Accept-Language"); value != "" {
+				return value, "", false
+			}
+		}
+	}
+
+	return "", "", false
+}
+
+// splitLanguagePrefix splits a URL path with a leading language segment (e.g.
+// "/fr/about") into the language code ("fr") and the rest of the path ("/about"), or
+// reports ok=false if the first segment isn't one of catalogs' configured languages.
+// Checking against catalogs (rather than just language.Parse) matters because plenty of
+// ordinary path segments ("api", "www", "app", "job", "new", "dev", "fun", "top"...)
+// happen to parse as valid, if obscure, language codes.
+func splitLanguagePrefix(path string, catalogs translationsCatalogs) (code string, rest string, ok bool) {
+	trimmed := strings.TrimPrefix(path, "/")
+	segment, remainder, hasRemainder := strings.Cut(trimmed, "/")
+	if segment == "" {
+		return "", "", false
+	}
+	tag, err := language.Parse(segment)
+	if err != nil {
+		return "", "", false
+	}
+	if _, configured := catalogs[tag]; !configured {
+		return "", "", false
+	}
+	if hasRemainder {
+		return segment, "/" + remainder, true
+	}
+	return segment, "/", true
+}
+
+// varyOn reports which headers the negotiation result depends on, for the Vary header:
+// Accept-Language when header negotiation is (possibly) used, Cookie when cookie
+// negotiation is (possibly) used. Path- and query-based negotiation don't need Vary
+// entries since they're part of the URL, which CDNs already key on.
+func varyOn(sources []string) []string {
+	if len(sources) == 0 {
+		sources = []string{defaultLanguageSource}
+	}
+
+	var vary []string
+	for _, raw := range sources {
+		switch parseLanguageSource(raw).kind {
+		case "header":
+			vary = append(vary, "Accept-Language")
+		case "cookie":
+			vary = append(vary, "Cookie")
+		}
+	}
+	return vary
+}
@@ -0,0 +1,35 @@
+package caddy_i18n
+
+import (
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+func TestSplitLanguagePrefix(t *testing.T) {
+	catalogs := translationsCatalogs{
+		language.French: {},
+		language.German: {},
+	}
+
+	tests := []struct {
+		path     string
+		wantCode string
+		wantRest string
+		wantOk   bool
+	}{
+		{"/fr/about", "fr", "/about", true},
+		{"/de", "de", "/", true},
+		{"/api/users", "", "", false},
+		{"/app/settings", "", "", false},
+		{"/", "", "", false},
+	}
+
+	for _, tt := range tests {
+		code, rest, ok := splitLanguagePrefix(tt.path, catalogs)
+		if code != tt.wantCode || rest != tt.wantRest || ok != tt.wantOk {
+			t.Errorf("splitLanguagePrefix(%q) = (%q, %q, %v), want (%q, %q, %v)",
+				tt.path, code, rest, ok, tt.wantCode, tt.wantRest, tt.wantOk)
+		}
+	}
+}
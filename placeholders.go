@@ -0,0 +1,59 @@
+package caddy_i18n
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// templateDataAttribute is the attribute holding a JSON object of placeholder values,
+// e.g. i18n-data='{"name":"Alice","count":3}'.
+const templateDataAttribute = "data"
+
+// renderPlaceholders substitutes the named placeholders in msgstr (a text/template
+// source, e.g. "Hello, {{.Name}}!") with the given data, formatting numbers through a
+// message.Printer for the target language so e.g. "3,14" comes out instead of "3.14"
+// where the locale expects a comma.
+func renderPlaceholders(lang language.Tag, msgstr string, data map[string]any) (string, error) {
+	if !strings.Contains(msgstr, "{{") {
+		return msgstr, nil
+	}
+
+	printer := message.NewPrinter(lang)
+	localized := make(map[string]any, len(data))
+	for key, value := range data {
+		localized[key] = localizeValue(printer, value)
+	}
+
+	tmpl, err := template.New("msgstr").Parse(msgstr)
+	if err != nil {
+		return "", fmt.Errorf("while parsing placeholder template: %w", err)
+	}
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, localized); err != nil {
+		return "", fmt.Errorf("while rendering placeholders: %w", err)
+	}
+	return rendered.String(), nil
+}
+
+// localizeValue formats numeric placeholder values (floats from i18n-data JSON, or
+// numeric-looking strings from individual i18n-data-* attributes) through the locale's
+// printer, leaving anything else untouched.
+func localizeValue(printer *message.Printer, value any) any {
+	switch v := value.(type) {
+	case float64:
+		return printer.Sprintf("%v", v)
+	case string:
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			return printer.Sprintf("%v", n)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,52 @@
+package caddy_i18n
+
+import (
+	"testing"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+func TestRenderPlaceholders(t *testing.T) {
+	got, err := renderPlaceholders(language.English, "Hello, {{.name}}!", map[string]any{"name": "Alice"})
+	if err != nil {
+		t.Fatalf("renderPlaceholders: %v", err)
+	}
+	if want := "Hello, Alice!"; got != want {
+		t.Errorf("renderPlaceholders() = %q, want %q", got, want)
+	}
+}
+
+func TestRenderPlaceholdersSkipsWithoutTemplateSyntax(t *testing.T) {
+	got, err := renderPlaceholders(language.English, "Hello, world!", map[string]any{"name": "Alice"})
+	if err != nil {
+		t.Fatalf("renderPlaceholders: %v", err)
+	}
+	if want := "Hello, world!"; got != want {
+		t.Errorf("renderPlaceholders() = %q, want %q", got, want)
+	}
+}
+
+func TestRenderPlaceholdersLocalizesNumbers(t *testing.T) {
+	got, err := renderPlaceholders(language.French, "Total: {{.amount}}", map[string]any{"amount": float64(1234)})
+	if err != nil {
+		t.Fatalf("renderPlaceholders: %v", err)
+	}
+	if want := "Total: 1 234"; got != want {
+		t.Errorf("renderPlaceholders() = %q, want %q", got, want)
+	}
+}
+
+func TestLocalizeValue(t *testing.T) {
+	printer := message.NewPrinter(language.French)
+
+	if got, want := localizeValue(printer, float64(1234)), "1 234"; got != want {
+		t.Errorf("localizeValue(float64) = %v, want %v", got, want)
+	}
+	if got, want := localizeValue(printer, "1234"), "1 234"; got != want {
+		t.Errorf("localizeValue(numeric string) = %v, want %v", got, want)
+	}
+	if got, want := localizeValue(printer, "hello"), "hello"; got != want {
+		t.Errorf("localizeValue(non-numeric string) = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,120 @@
+package caddy_i18n
+
+import "golang.org/x/text/language"
+
+// pluralCategory is a CLDR plural category, as used to pick the msgstr[] entry
+// a translator filled in for a given quantity.
+type pluralCategory string
+
+const (
+	pluralZero  pluralCategory = "zero"
+	pluralOne   pluralCategory = "one"
+	pluralTwo   pluralCategory = "two"
+	pluralFew   pluralCategory = "few"
+	pluralMany  pluralCategory = "many"
+	pluralOther pluralCategory = "other"
+)
+
+// pluralCategories lists, in the msgstr[] order gettext expects, the plural categories
+// a language distinguishes. This is a hand-ported subset of the CLDR plural rules
+// covering the language families this module is likely to serve; anything not listed
+// falls back to the English-like one/other split.
+var pluralCategories = map[string][]pluralCategory{
+	"ja": {pluralOther},
+	"ko": {pluralOther},
+	"zh": {pluralOther},
+	"vi": {pluralOther},
+	"th": {pluralOther},
+	"id": {pluralOther},
+	"fr": {pluralOne, pluralOther},
+	"pt": {pluralOne, pluralOther},
+	"ru": {pluralOne, pluralFew, pluralMany, pluralOther},
+	"uk": {pluralOne, pluralFew, pluralMany, pluralOther},
+	"pl": {pluralOne, pluralFew, pluralMany, pluralOther},
+	"ar": {pluralZero, pluralOne, pluralTwo, pluralFew, pluralMany, pluralOther},
+}
+
+// isIntegral reports whether n has no fractional part (the CLDR operand "v == 0").
+func isIntegral(n float64) bool {
+	return n == float64(int64(n))
+}
+
+// pluralCategoryFor computes the CLDR plural category for n (the "count" operand)
+// in the given language, using the integer-sample rules for each family in
+// pluralCategories. Fractional values are treated as "other" everywhere except fr/pt,
+// where any n from 0 up to (but excluding) 2 is "one".
+func pluralCategoryFor(tag language.Tag, n float64) pluralCategory {
+	base, _ := tag.Base()
+	switch base.String() {
+	case "ja", "ko", "zh", "vi", "th", "id":
+		return pluralOther
+	case "fr", "pt":
+		if n >= 0 && n < 2 {
+			return pluralOne
+		}
+		return pluralOther
+	case "ru", "uk":
+		mod10, mod100 := int(n)%10, int(n)%100
+		switch {
+		case !isIntegral(n):
+			return pluralOther
+		case mod10 == 1 && mod100 != 11:
+			return pluralOne
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return pluralFew
+		default:
+			return pluralMany
+		}
+	case "pl":
+		mod10, mod100 := int(n)%10, int(n)%100
+		switch {
+		case n == 1:
+			return pluralOne
+		case !isIntegral(n):
+			return pluralOther
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return pluralFew
+		default:
+			return pluralMany
+		}
+	case "ar":
+		mod100 := int(n) % 100
+		switch {
+		case n == 0:
+			return pluralZero
+		case n == 1:
+			return pluralOne
+		case n == 2:
+			return pluralTwo
+		case isIntegral(n) && mod100 >= 3 && mod100 <= 10:
+			return pluralFew
+		case isIntegral(n) && mod100 >= 11:
+			return pluralMany
+		default:
+			return pluralOther
+		}
+	default:
+		if n == 1 {
+			return pluralOne
+		}
+		return pluralOther
+	}
+}
+
+// pluralFormIndex returns the msgstr[] index matching n for the catalog's language,
+// i.e. the position of n's plural category in pluralCategories, falling back to the
+// last (always "other") entry when the category isn't one the language distinguishes.
+func pluralFormIndex(tag language.Tag, n float64) int {
+	base, _ := tag.Base()
+	categories, ok := pluralCategories[base.String()]
+	if !ok {
+		categories = []pluralCategory{pluralOne, pluralOther}
+	}
+	category := pluralCategoryFor(tag, n)
+	for i, c := range categories {
+		if c == category {
+			return i
+		}
+	}
+	return len(categories) - 1
+}
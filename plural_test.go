@@ -0,0 +1,76 @@
+package caddy_i18n
+
+import (
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+func TestPluralCategoryFor(t *testing.T) {
+	tests := []struct {
+		lang language.Tag
+		n    float64
+		want pluralCategory
+	}{
+		{language.English, 1, pluralOne},
+		{language.English, 2, pluralOther},
+		{language.French, 0, pluralOne},
+		{language.French, 1, pluralOne},
+		{language.French, 2, pluralOther},
+		{language.Russian, 1, pluralOne},
+		{language.Russian, 2, pluralFew},
+		{language.Russian, 5, pluralMany},
+		{language.Russian, 11, pluralMany},
+		{language.Russian, 21, pluralOne},
+		{language.Russian, 1.5, pluralOther},
+		{language.Polish, 1, pluralOne},
+		{language.Polish, 2, pluralFew},
+		{language.Polish, 5, pluralMany},
+		{language.Polish, 1.5, pluralOther},
+		{language.Arabic, 0, pluralZero},
+		{language.Arabic, 1, pluralOne},
+		{language.Arabic, 2, pluralTwo},
+		{language.Arabic, 3, pluralFew},
+		{language.Arabic, 11, pluralMany},
+		{language.Arabic, 100, pluralOther},
+		{language.Japanese, 1, pluralOther},
+		{language.Japanese, 5, pluralOther},
+	}
+
+	for _, tt := range tests {
+		got := pluralCategoryFor(tt.lang, tt.n)
+		if got != tt.want {
+			t.Errorf("pluralCategoryFor(%v, %v) = %q, want %q", tt.lang, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestPluralFormIndex(t *testing.T) {
+	tests := []struct {
+		lang language.Tag
+		n    float64
+		want int
+	}{
+		{language.English, 1, 0},
+		{language.English, 2, 1},
+		{language.French, 1, 0},
+		{language.French, 2, 1},
+		{language.Russian, 1, 0},
+		{language.Russian, 2, 1},
+		{language.Russian, 5, 2},
+		{language.Russian, 11, 2},
+		{language.Arabic, 0, 0},
+		{language.Arabic, 1, 1},
+		{language.Arabic, 2, 2},
+		{language.Arabic, 3, 3},
+		{language.Arabic, 11, 4},
+		{language.Arabic, 100, 5},
+	}
+
+	for _, tt := range tests {
+		got := pluralFormIndex(tt.lang, tt.n)
+		if got != tt.want {
+			t.Errorf("pluralFormIndex(%v, %v) = %d, want %d", tt.lang, tt.n, got, tt.want)
+		}
+	}
+}
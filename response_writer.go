@@ -0,0 +1,86 @@
+package caddy_i18n
+
+import (
+	"io"
+	"net/http"
+	"strings"
+)
+
+// translatingResponseWriter wraps the http.ResponseWriter passed to the next handler,
+// streaming its body through translateTokens as soon as the status code and headers
+// say it's HTML (see shouldTranslate): everything the inner handler writes is tokenized
+// and translated on the fly, instead of being buffered into memory for the whole page.
+// Responses that aren't translatable (non-HTML, or a status outside 2xx/3xx) pass
+// straight through.
+type translatingResponseWriter struct {
+	http.ResponseWriter
+	translations *translationCatalog
+
+	headerWritten bool
+	translating   bool
+	pipeWriter    *io.PipeWriter
+	done          chan error
+}
+
+func newTranslatingResponseWriter(w http.ResponseWriter, translations *translationCatalog) *translatingResponseWriter {
+	return &translatingResponseWriter{ResponseWriter: w, translations: translations}
+}
+
+// WriteHeader decides, from the status code and the headers the inner handler set, whether
+// the body that follows should be translated, then (if so) starts the tokenizer goroutine
+// that reads from a pipe and writes the translated output straight to the real ResponseWriter.
+func (tw *translatingResponseWriter) WriteHeader(status int) {
+	if tw.headerWritten {
+		return
+	}
+	tw.headerWritten = true
+	tw.translating = shouldTranslate(status, tw.Header())
+
+	if tw.translating {
+		// The translated body's length isn't known upfront.
+		tw.Header().Del("Content-Length")
+
+		pipeReader, pipeWriter := io.Pipe()
+		tw.pipeWriter = pipeWriter
+		tw.done = make(chan error, 1)
+		go func() {
+			tw.done <- tw.translations.translateTokens(pipeReader, tw.ResponseWriter)
+			pipeReader.Close()
+		}()
+	}
+
+	tw.ResponseWriter.WriteHeader(status)
+}
+
+func (tw *translatingResponseWriter) Write(p []byte) (int, error) {
+	if !tw.headerWritten {
+		tw.WriteHeader(http.StatusOK)
+	}
+	if tw.translating {
+		return tw.pipeWriter.Write(p)
+	}
+	return tw.ResponseWriter.Write(p)
+}
+
+// Close signals the end of the response body to the tokenizer goroutine and waits for
+// it to finish draining, returning any translation error it hit.
+func (tw *translatingResponseWriter) Close() error {
+	if !tw.translating {
+		return nil
+	}
+	tw.pipeWriter.Close()
+	return <-tw.done
+}
+
+func (tw *translatingResponseWriter) Flush() {
+	if flusher, ok := tw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// shouldTranslate reports whether a response with the given status and headers should
+// be translated: successful or redirecting HTML responses, same criteria the handler
+// used to buffer on before streaming.
+func shouldTranslate(status int, header http.Header) bool {
+	return status >= 200 && status < 400 && strings.HasPrefix(header.Get("Content-Type"), "text/html")
+}
@@ -0,0 +1,376 @@
+package caddy_i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ortfo/gettext/po"
+	"go.uber.org/zap"
+	"golang.org/x/net/html"
+	"golang.org/x/text/message"
+)
+
+// translateTokens reads HTML from r token by token and writes the translated page to w
+// as it goes: everything outside a translation marker is written through immediately
+// (and flushed, if w supports it), so the client can start receiving <head> while the
+// rest of the page is still being generated upstream. Only the inner content of a
+// marker subtree (an element named t.markerTag, or carrying t.markerAttribute) is
+// buffered, for just long enough to resolve its translation.
+func (t *translationCatalog) translateTokens(r io.Reader, w io.Writer) error {
+	flusher, _ := w.(http.Flusher)
+	tokenizer := html.NewTokenizer(r)
+
+	var capture *markerCapture
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if err := tokenizer.Err(); err != io.EOF {
+				return fmt.Errorf("while tokenizing page: %w", err)
+			}
+			return nil
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			selfClosing := token.Type == html.SelfClosingTagToken
+
+			if capture != nil {
+				if token.Data == capture.tag {
+					capture.depth++
+				}
+				capture.raw.WriteString(token.String())
+				continue
+			}
+
+			if skip := t.keepOnSkip(token); skip {
+				if selfClosing {
+					// No end tag will ever arrive to close a capture for a self-closing
+					// tag, and there's no inner content to discard anyway: just drop it.
+					continue
+				}
+				capture = newSkipCapture(token)
+				continue
+			}
+
+			if newCapture, ok := t.startMarkerCapture(token); ok {
+				if selfClosing {
+					// A self-closing marker (<i18n i18n-count="1"/>) has no inner HTML
+					// and no end tag will ever arrive to close a capture, so render it
+					// immediately instead of opening one nothing will ever close.
+					if newCapture.tag != t.markerTag {
+						io.WriteString(w, t.rewriteMarkerStartTag(token))
+					}
+					translated, err := t.renderCapture(newCapture)
+					if err != nil {
+						return err
+					}
+					io.WriteString(w, translated)
+					flush(flusher)
+					continue
+				}
+
+				// The dedicated marker tag (<i18n>) is stripped entirely; an arbitrary
+				// element carrying the marker attribute keeps its own start/end tags,
+				// with just the marker-defining attributes removed.
+				if newCapture.tag != t.markerTag {
+					io.WriteString(w, t.rewriteMarkerStartTag(token))
+					flush(flusher)
+				}
+				capture = newCapture
+				continue
+			}
+
+			io.WriteString(w, t.rewriteStartTag(token))
+			flush(flusher)
+
+		case html.EndTagToken:
+			token := tokenizer.Token()
+
+			if capture != nil {
+				if token.Data != capture.tag {
+					capture.raw.WriteString(token.String())
+					continue
+				}
+				if capture.depth > 0 {
+					capture.depth--
+					capture.raw.WriteString(token.String())
+					continue
+				}
+
+				if !capture.discard {
+					translated, err := t.renderCapture(capture)
+					if err != nil {
+						return err
+					}
+					io.WriteString(w, translated)
+					if capture.tag != t.markerTag {
+						io.WriteString(w, token.String())
+					}
+				}
+				capture = nil
+				flush(flusher)
+				continue
+			}
+
+			if token.Data == "head" && t.exposeToJS {
+				fmt.Fprintf(w, "<script>window.i18nLanguage = %q; window.i18nSourceLanguage = %q;</script>", t.language, t.sourceLanguage)
+			}
+			io.WriteString(w, token.String())
+			flush(flusher)
+
+		default:
+			token := tokenizer.Token()
+			if capture != nil {
+				capture.raw.WriteString(token.String())
+				continue
+			}
+			io.WriteString(w, token.String())
+			flush(flusher)
+		}
+	}
+}
+
+func flush(flusher http.Flusher) {
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+// markerCapture accumulates the raw inner HTML of a marker subtree (or, when discard is
+// set, of a subtree being dropped because of a mismatched i18n-keep-on) between its
+// opening and matching closing tag.
+type markerCapture struct {
+	tag         string
+	depth       int
+	discard     bool
+	msgContext  string
+	msgIdPlural string
+	count       string
+	hasCount    bool
+	data        map[string]any
+	raw         strings.Builder
+}
+
+func newSkipCapture(token html.Token) *markerCapture {
+	return &markerCapture{tag: token.Data, discard: true}
+}
+
+// keepOnSkip reports whether token carries an i18n-keep-on attribute whose value
+// doesn't match the catalog's language, meaning its whole subtree should be dropped
+// (used for content that's already in one specific language, e.g. user-generated text).
+func (t *translationCatalog) keepOnSkip(token html.Token) bool {
+	keepOnAttr := fmt.Sprintf("%s-keep-on", t.markerAttribute)
+	for _, attribute := range token.Attr {
+		if attribute.Key == keepOnAttr {
+			return attribute.Val != t.language.String()
+		}
+	}
+	return false
+}
+
+// startMarkerCapture reports whether token opens a translation marker (it's named
+// t.markerTag, or carries t.markerAttribute), and if so returns a markerCapture primed
+// with the plural/context/template-data attributes read off it.
+func (t *translationCatalog) startMarkerCapture(token html.Token) (*markerCapture, bool) {
+	isMarker := token.Data == t.markerTag
+	if !isMarker {
+		for _, attribute := range token.Attr {
+			if attribute.Key == t.markerAttribute {
+				isMarker = true
+				break
+			}
+		}
+	}
+	if !isMarker {
+		return nil, false
+	}
+
+	parsed := parseMarkerAttrs(token.Attr, newMarkerAttrNames(t.markerAttribute))
+	capture := &markerCapture{
+		tag:         token.Data,
+		msgContext:  parsed.context,
+		msgIdPlural: parsed.idPlural,
+		count:       parsed.count,
+		hasCount:    parsed.hasCount,
+		data:        make(map[string]any),
+	}
+
+	blobAttribute := fmt.Sprintf("%s-%s", t.markerAttribute, templateDataAttribute)
+	dataPrefix := blobAttribute + "-"
+	for _, attribute := range token.Attr {
+		switch {
+		case attribute.Key == blobAttribute:
+			var blob map[string]any
+			if err := json.Unmarshal([]byte(attribute.Val), &blob); err != nil {
+				t.Warn("invalid i18n-data JSON", zap.String("data", attribute.Val), zap.Error(err))
+				continue
+			}
+			for key, value := range blob {
+				capture.data[key] = value
+			}
+		case strings.HasPrefix(attribute.Key, dataPrefix):
+			capture.data[strings.TrimPrefix(attribute.Key, dataPrefix)] = attribute.Val
+		}
+	}
+	return capture, true
+}
+
+// rewriteMarkerStartTag returns a non-markerTag marker element's own start tag, with the
+// attributes that define it as a marker (the marker attribute itself, plus its
+// -context/-plural/-count/-data/-data-* companions) stripped, analogous to
+// rewriteStartTag stripping i18n-attrs.
+func (t *translationCatalog) rewriteMarkerStartTag(token html.Token) string {
+	names := newMarkerAttrNames(t.markerAttribute)
+	blobAttribute := fmt.Sprintf("%s-%s", t.markerAttribute, templateDataAttribute)
+	dataPrefix := blobAttribute + "-"
+
+	rewritten := token.Attr[:0]
+	for _, attribute := range token.Attr {
+		switch {
+		case attribute.Key == t.markerAttribute,
+			attribute.Key == names.context,
+			attribute.Key == names.plural,
+			attribute.Key == names.count,
+			attribute.Key == blobAttribute,
+			strings.HasPrefix(attribute.Key, dataPrefix):
+			continue
+		default:
+			rewritten = append(rewritten, attribute)
+		}
+	}
+	token.Attr = rewritten
+	return token.String()
+}
+
+// renderCapture resolves the translation (plain or plural) for a marker's buffered
+// inner HTML, renders any i18n-data placeholders into it, and records it as missing if
+// no translation was found.
+func (t *translationCatalog) renderCapture(capture *markerCapture) (string, error) {
+	innerHTML := strings.TrimSpace(html.UnescapeString(capture.raw.String()))
+	if innerHTML == "" {
+		return "", nil
+	}
+
+	var n float64
+	if capture.hasCount {
+		n, _ = strconv.ParseFloat(capture.count, 64)
+		capture.data["count"] = n
+	}
+
+	msgstr := innerHTML
+	if t.language != t.sourceLanguage {
+		var translated string
+		var err error
+		if capture.hasCount && capture.msgIdPlural != "" {
+			translated, err = t.getTranslationN(innerHTML, capture.msgIdPlural, capture.msgContext, n)
+		} else {
+			translated, err = t.getTranslation(innerHTML, capture.msgContext)
+		}
+		if err != nil {
+			t.missingMessages = append(t.missingMessages, po.Message{
+				MsgId:       innerHTML,
+				MsgIdPlural: capture.msgIdPlural,
+				MsgContext:  capture.msgContext,
+			})
+		} else {
+			msgstr = translated
+		}
+	}
+
+	if capture.hasCount {
+		// i18n-count/i18n-plural catalogs predate i18n-data templating and use a bare
+		// {{count}} token rather than text/template's {{.count}}; substitute it
+		// literally so those catalogs keep working. This must run regardless of
+		// whether a translation was applied, since the source-language text itself
+		// carries the same {{count}} token.
+		msgstr = strings.ReplaceAll(msgstr, "{{count}}", message.NewPrinter(t.language).Sprint(n))
+	}
+
+	if len(capture.data) > 0 {
+		rendered, err := renderPlaceholders(t.language, msgstr, capture.data)
+		if err != nil {
+			t.Warn("while rendering placeholders", zap.String("msgstr", msgstr), zap.Error(err))
+		} else {
+			msgstr = rendered
+		}
+	}
+
+	return msgstr, nil
+}
+
+// rewriteStartTag translates any i18n-attrs-marked attributes on a non-marker start tag
+// and returns the tag's HTML, with the marker-only attributes stripped: i18n-attrs (and
+// the attributes it marks for translation) as well as i18n-keep-on, which keepOnSkip
+// already consumed to decide whether to keep this element at all.
+func (t *translationCatalog) rewriteStartTag(token html.Token) string {
+	keepOnAttribute := fmt.Sprintf("%s-keep-on", t.markerAttribute)
+
+	hasAttrsMarker, hasKeepOn := false, false
+	for _, attribute := range token.Attr {
+		switch attribute.Key {
+		case attrsFlagName(t.markerAttribute):
+			hasAttrsMarker = true
+		case keepOnAttribute:
+			hasKeepOn = true
+		}
+	}
+	if !hasAttrsMarker && !hasKeepOn {
+		return token.String()
+	}
+
+	rewritten := token.Attr[:0]
+	for _, attribute := range token.Attr {
+		if attribute.Key == keepOnAttribute {
+			continue
+		}
+		targetKey, isCommaList, matched := classifyAttrsTarget(attribute.Key, t.markerAttribute)
+		if !matched {
+			rewritten = append(rewritten, attribute)
+			continue
+		}
+		if targetKey == "" {
+			continue
+		}
+		value := attribute.Val
+		if isCommaList {
+			value = t.translateCommaList(value)
+		} else {
+			value = t.translateAttr(value)
+		}
+		rewritten = append(rewritten, html.Attribute{Key: targetKey, Val: value})
+	}
+	token.Attr = rewritten
+	return token.String()
+}
+
+// translateAttr translates a single attribute value, falling back to the original value
+// (and recording it as missing) when there's no translation for it.
+func (t *translationCatalog) translateAttr(value string) string {
+	if t.language == t.sourceLanguage {
+		return value
+	}
+	translated, err := t.getTranslation(value, "")
+	if err != nil {
+		t.Warn("missing translation", zap.String("msgid", value))
+		t.missingMessages = append(t.missingMessages, po.Message{MsgId: value})
+		return value
+	}
+	return translated
+}
+
+// translateCommaList translates each comma-separated item of a multi-valued attribute
+// (i18n:commas:...) independently and rejoins them.
+func (t *translationCatalog) translateCommaList(value string) string {
+	if t.language == t.sourceLanguage {
+		return value
+	}
+	items := strings.Split(value, ",")
+	for i, item := range items {
+		items[i] = t.translateAttr(item)
+	}
+	return strings.Join(items, ",")
+}
@@ -0,0 +1,124 @@
+package caddy_i18n
+
+import (
+	"strings"
+	"testing"
+
+	mapset "github.com/deckarep/golang-set"
+	"github.com/ortfo/gettext/po"
+	"go.uber.org/zap"
+	"golang.org/x/text/language"
+)
+
+func newTestCatalog() *translationCatalog {
+	return &translationCatalog{
+		poFile:          &po.File{},
+		seenMessages:    mapset.NewSet(),
+		language:        language.English,
+		sourceLanguage:  language.English,
+		markerAttribute: "i18n",
+		markerTag:       "i18n",
+		format:          poCatalogFormat{},
+		Logger:          zap.NewNop(),
+	}
+}
+
+func translate(t *testing.T, html string) string {
+	t.Helper()
+	catalog := newTestCatalog()
+	var out strings.Builder
+	if err := catalog.translateTokens(strings.NewReader(html), &out); err != nil {
+		t.Fatalf("translateTokens: %v", err)
+	}
+	return out.String()
+}
+
+func TestTranslateTokensPreservesNonMarkerTagWrapper(t *testing.T) {
+	got := translate(t, `<div i18n>Hello</div>`)
+	want := `<div>Hello</div>`
+	if got != want {
+		t.Errorf("translate(%q) = %q, want %q", `<div i18n>Hello</div>`, got, want)
+	}
+}
+
+func TestTranslateTokensStripsDedicatedMarkerTag(t *testing.T) {
+	got := translate(t, `<i18n>Hello</i18n>`)
+	want := `Hello`
+	if got != want {
+		t.Errorf("translate(%q) = %q, want %q", `<i18n>Hello</i18n>`, got, want)
+	}
+}
+
+func TestTranslateTokensStripsKeepOnAttribute(t *testing.T) {
+	got := translate(t, `<div i18n-keep-on="en">Hello</div>`)
+	want := `<div>Hello</div>`
+	if got != want {
+		t.Errorf("translate(%q) = %q, want %q", `<div i18n-keep-on="en">Hello</div>`, got, want)
+	}
+}
+
+func TestTranslateTokensSubstitutesCountInSourceLanguage(t *testing.T) {
+	html := `<i18n i18n-count="3" i18n-plural="items">{{count}} item</i18n>`
+	got := translate(t, html)
+	want := `3 item`
+	if got != want {
+		t.Errorf("translate(%q) = %q, want %q", html, got, want)
+	}
+}
+
+func TestTranslateTokensHandlesSelfClosingMarker(t *testing.T) {
+	html := `<html><body><i18n i18n-count="1"/><div>after text that should appear</div></body></html>`
+	got := translate(t, html)
+	want := `<html><body><div>after text that should appear</div></body></html>`
+	if got != want {
+		t.Errorf("translate(%q) = %q, want %q", html, got, want)
+	}
+}
+
+func TestTranslateTokensHandlesSelfClosingKeepOn(t *testing.T) {
+	html := `<html><body><div i18n-keep-on="fr"/><div>after</div></body></html>`
+	got := translate(t, html)
+	want := `<html><body><div>after</div></body></html>`
+	if got != want {
+		t.Errorf("translate(%q) = %q, want %q", html, got, want)
+	}
+}
+
+func TestTranslateTokensCombinesCountAndDataInSourceLanguage(t *testing.T) {
+	html := `<i18n i18n-count="3" i18n-data='{"name":"Alice"}'>{{count}} item for {{.name}}</i18n>`
+	got := translate(t, html)
+	want := `3 item for Alice`
+	if got != want {
+		t.Errorf("translate(%q) = %q, want %q", html, got, want)
+	}
+}
+
+func TestTranslateTokensCombinesCountAndDataInTargetLanguage(t *testing.T) {
+	poFile := &po.File{Messages: []po.Message{
+		{
+			MsgId:        "{{.count}} item for {{.name}}",
+			MsgIdPlural:  "{{.count}} items for {{.name}}",
+			MsgStrPlural: []string{"{{count}} objet pour {{.name}}", "{{count}} objets pour {{.name}}"},
+		},
+	}}
+	catalog := &translationCatalog{
+		poFile:          poFile,
+		seenMessages:    mapset.NewSet(),
+		language:        language.French,
+		sourceLanguage:  language.English,
+		markerAttribute: "i18n",
+		markerTag:       "i18n",
+		format:          poCatalogFormat{},
+		Logger:          zap.NewNop(),
+	}
+
+	html := `<i18n i18n-count="2" i18n-plural="{{.count}} items for {{.name}}" i18n-data='{"name":"Alice"}'>{{.count}} item for {{.name}}</i18n>`
+	var out strings.Builder
+	if err := catalog.translateTokens(strings.NewReader(html), &out); err != nil {
+		t.Fatalf("translateTokens: %v", err)
+	}
+	want := `2 objets pour Alice`
+	if out.String() != want {
+		t.Errorf("translate(%q) = %q, want %q", html, out.String(), want)
+	}
+}
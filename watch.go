@@ -0,0 +1,176 @@
+package caddy_i18n
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+	"golang.org/x/text/language"
+)
+
+// watchDebounce coalesces the burst of fsnotify events an editor's save produces (e.g.
+// write-to-temp-then-rename) into a single reload.
+const watchDebounce = 200 * time.Millisecond
+
+// watchTranslations starts an fsnotify watcher on m.Translations and atomically swaps
+// the affected language's catalog into m.state whenever a catalog file changes, is
+// created, or is removed, so editing a .po file takes effect without a Caddy config
+// reload. ctx.OnCancel stops the watcher when the module is unprovisioned.
+func (m *I18n) watchTranslations(ctx caddy.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("while starting translations watcher: %w", err)
+	}
+	if err := watcher.Add(m.Translations); err != nil {
+		watcher.Close()
+		return fmt.Errorf("while watching %s: %w", m.Translations, err)
+	}
+
+	var mu sync.Mutex
+	timers := make(map[string]*time.Timer)
+	// swapMu serializes reloads/unloads of different files, whose timers can fire
+	// concurrently, so one's load-copy-store of m.state can't revert the other's.
+	var swapMu sync.Mutex
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) == 0 {
+					continue
+				}
+
+				path := event.Name
+				mu.Lock()
+				if timer, scheduled := timers[path]; scheduled {
+					timer.Reset(watchDebounce)
+				} else {
+					timers[path] = time.AfterFunc(watchDebounce, func() {
+						mu.Lock()
+						delete(timers, path)
+						mu.Unlock()
+						// Whether the file still exists once the burst has settled decides
+						// between reload and unload, so a delete-then-recreate save reloads.
+						swapMu.Lock()
+						defer swapMu.Unlock()
+						if _, err := os.Stat(path); err != nil {
+							m.unloadCatalog(path)
+						} else {
+							m.reloadCatalog(path)
+						}
+					})
+				}
+				mu.Unlock()
+
+			case watchErr, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				m.Logger.Warn("translations watcher error", zap.Error(watchErr))
+			}
+		}
+	}()
+
+	ctx.OnCancel(func() {
+		watcher.Close()
+	})
+
+	return nil
+}
+
+// catalogLanguageFromPath derives the configured language the watched catalog file at
+// path belongs to, reporting ok=false if path isn't one of m.Languages' catalog files
+// (e.g. an unrelated file dropped into the translations directory), so reloadCatalog and
+// unloadCatalog agree on which files they care about.
+func (m *I18n) catalogLanguageFromPath(path string) (languageCode language.Tag, ok bool) {
+	format, err := resolveCatalogFormat(m.Format)
+	if err != nil {
+		m.Logger.Warn("cannot reload catalogs, invalid format", zap.Error(err))
+		return language.Tag{}, false
+	}
+
+	languageCode, err = language.Parse(strings.TrimSuffix(filepath.Base(path), "."+format.Extension()))
+	if err != nil {
+		return language.Tag{}, false
+	}
+
+	for _, configured := range m.Languages {
+		if configured == languageCode.String() {
+			return languageCode, true
+		}
+	}
+	return language.Tag{}, false
+}
+
+// reloadCatalog re-parses the catalog file at path and swaps it into m.state, rebuilding
+// the language matcher so it stays in sync with the catalogs it was built from. Load
+// errors are logged rather than propagated, so a half-saved catalog file doesn't take
+// the handler down.
+func (m *I18n) reloadCatalog(path string) {
+	languageCode, ok := m.catalogLanguageFromPath(path)
+	if !ok {
+		return
+	}
+
+	format, err := resolveCatalogFormat(m.Format)
+	if err != nil {
+		return
+	}
+
+	sourceLanguage, err := language.Parse(m.SourceLanguage)
+	if err != nil {
+		return
+	}
+
+	poFile, err := format.Load(path)
+	if err != nil {
+		m.Logger.Warn("while reloading catalog", zap.String("lang", languageCode.String()), zap.Error(err))
+		return
+	}
+	poFile.SetSourceLanguage(sourceLanguage)
+
+	current := m.state.Load().catalogs
+	next := make(translationsCatalogs, len(current))
+	for lang, catalog := range current {
+		next[lang] = catalog
+	}
+	next[languageCode] = m.newCatalog(languageCode, sourceLanguage, poFile, format)
+
+	m.state.Store(newCatalogState(next))
+	m.Logger.Info("reloaded catalog", zap.String("lang", languageCode.String()))
+}
+
+// unloadCatalog drops the catalog for the language whose file at path was deleted and
+// rebuilds the matcher, so a removed catalog file stops being served instead of the
+// stale in-memory catalog staying loaded forever.
+func (m *I18n) unloadCatalog(path string) {
+	languageCode, ok := m.catalogLanguageFromPath(path)
+	if !ok {
+		return
+	}
+
+	current := m.state.Load().catalogs
+	if _, loaded := current[languageCode]; !loaded {
+		return
+	}
+
+	next := make(translationsCatalogs, len(current)-1)
+	for lang, catalog := range current {
+		if lang == languageCode {
+			continue
+		}
+		next[lang] = catalog
+	}
+
+	m.state.Store(newCatalogState(next))
+	m.Logger.Info("unloaded removed catalog", zap.String("lang", languageCode.String()))
+}
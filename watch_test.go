@@ -0,0 +1,104 @@
+package caddy_i18n
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"go.uber.org/zap"
+	"golang.org/x/text/language"
+)
+
+func newTestI18n(t *testing.T) *I18n {
+	t.Helper()
+	m := &I18n{
+		Translations:   t.TempDir(),
+		SourceLanguage: "en",
+		Languages:      []string{"fr"},
+		Format:         "po",
+		Logger:         zap.NewNop(),
+	}
+	m.state = &atomic.Pointer[catalogState]{}
+	m.state.Store(newCatalogState(translationsCatalogs{}))
+	return m
+}
+
+func writeTestPO(t *testing.T, path, contents string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+}
+
+func TestCatalogLanguageFromPathRejectsUnconfiguredFile(t *testing.T) {
+	m := newTestI18n(t)
+	if _, ok := m.catalogLanguageFromPath(filepath.Join(m.Translations, "notes.txt")); ok {
+		t.Errorf("expected unconfigured file to be rejected")
+	}
+	if _, ok := m.catalogLanguageFromPath(filepath.Join(m.Translations, "de.po")); ok {
+		t.Errorf("expected unconfigured language to be rejected")
+	}
+	code, ok := m.catalogLanguageFromPath(filepath.Join(m.Translations, "fr.po"))
+	if !ok || code != language.French {
+		t.Errorf("catalogLanguageFromPath(fr.po) = (%v, %v), want (%v, true)", code, ok, language.French)
+	}
+}
+
+func TestReloadCatalogLoadsNewCatalog(t *testing.T) {
+	m := newTestI18n(t)
+	path := filepath.Join(m.Translations, "fr.po")
+	writeTestPO(t, path, "msgid \"hello\"\nmsgstr \"bonjour\"\n")
+
+	m.reloadCatalog(path)
+
+	catalog, loaded := m.state.Load().catalogs[language.French]
+	if !loaded {
+		t.Fatalf("expected catalog for fr to be loaded after reload")
+	}
+	translation, err := catalog.getTranslation("hello", "")
+	if err != nil || translation != "bonjour" {
+		t.Errorf("getTranslation(hello) = (%q, %v), want (bonjour, nil)", translation, err)
+	}
+}
+
+func TestReloadCatalogIgnoresUnconfiguredFile(t *testing.T) {
+	m := newTestI18n(t)
+	path := filepath.Join(m.Translations, "de.po")
+	writeTestPO(t, path, "msgid \"hello\"\nmsgstr \"hallo\"\n")
+
+	m.reloadCatalog(path)
+
+	if _, loaded := m.state.Load().catalogs[language.German]; loaded {
+		t.Errorf("expected unconfigured language de not to be loaded")
+	}
+}
+
+func TestUnloadCatalogRemovesEntry(t *testing.T) {
+	m := newTestI18n(t)
+	path := filepath.Join(m.Translations, "fr.po")
+	writeTestPO(t, path, "msgid \"hello\"\nmsgstr \"bonjour\"\n")
+	m.reloadCatalog(path)
+
+	if _, loaded := m.state.Load().catalogs[language.French]; !loaded {
+		t.Fatalf("precondition failed: catalog for fr should be loaded")
+	}
+
+	os.Remove(path)
+	m.unloadCatalog(path)
+
+	if _, loaded := m.state.Load().catalogs[language.French]; loaded {
+		t.Errorf("expected catalog for fr to be unloaded after removal")
+	}
+}
+
+func TestUnloadCatalogNoopsWhenNotLoaded(t *testing.T) {
+	m := newTestI18n(t)
+	path := filepath.Join(m.Translations, "fr.po")
+
+	m.unloadCatalog(path)
+
+	if len(m.state.Load().catalogs) != 0 {
+		t.Errorf("expected unloadCatalog to be a no-op when nothing was loaded, got %d catalogs", len(m.state.Load().catalogs))
+	}
+}